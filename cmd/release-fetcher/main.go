@@ -0,0 +1,116 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// release-fetcher reads a declarative packages.yaml manifest and downloads
+// the configured releases into cmd/operator/kodata, without requiring
+// operators to fork this repository to fetch a different set of packages.
+//
+// With --check, it resolves versions but skips downloading anything,
+// exiting non-zero if any release's manifest.lock would change -- for
+// gating a release pipeline on manifests having already been regenerated.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+
+	"knative.dev/operator/pkg/packages"
+)
+
+func main() {
+	manifestPath := flag.String("manifest", "packages.yaml", "path to the packages manifest")
+	minors := flag.Int("minors", 1, "number of minor releases (including all patches) to fetch per package")
+	check := flag.Bool("check", false, "resolve versions without downloading; exit non-zero if any lockfile would change")
+	flag.Parse()
+
+	changed, err := run(context.Background(), *manifestPath, *minors, *check)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *check && changed {
+		os.Exit(1)
+	}
+}
+
+// run returns whether regeneration would change any release's resolved
+// versions (only meaningful, and only computed without side effects, when
+// check is true).
+func run(ctx context.Context, manifestPath string, minors int, check bool) (bool, error) {
+	data, err := ioutil.ReadFile(manifestPath)
+	if err != nil {
+		return false, fmt.Errorf("unable to read %s: %w", manifestPath, err)
+	}
+
+	m, err := packages.LoadManifest(data)
+	if err != nil {
+		return false, err
+	}
+
+	httpClient := http.DefaultClient
+	pkgs, err := m.BuildPackages(httpClient)
+	if err != nil {
+		return false, err
+	}
+
+	// List every source's releases once, up front, so that Additional
+	// sources can be aligned against each other regardless of fetch
+	// order.
+	allReleases := map[string][]packages.Release{}
+	for _, p := range pkgs {
+		sources := append([]packages.PackageSource{p.Primary}, p.Additional...)
+		for _, src := range sources {
+			key := src.String()
+			if _, ok := allReleases[key]; ok {
+				continue
+			}
+			releases, err := src.Source.ListReleases(ctx, src.Org, src.Repo)
+			if err != nil {
+				return false, fmt.Errorf("unable to list releases for %s: %w", key, err)
+			}
+			allReleases[key] = releases
+		}
+	}
+
+	changed := false
+	d := packages.NewDownloader()
+	for _, p := range pkgs {
+		releases := packages.LastN(minors, allReleases[p.Primary.String()])
+		for _, r := range releases {
+			if check {
+				diff, err := packages.CheckRelease(p, r, allReleases)
+				if err != nil {
+					return false, fmt.Errorf("unable to check release %s: %w", r, err)
+				}
+				for _, line := range diff {
+					log.Printf("%s: %s", r, line)
+				}
+				changed = changed || len(diff) > 0
+				continue
+			}
+
+			log.Printf("Fetching %s", r)
+			if err := packages.HandleRelease(ctx, d, p, r, allReleases); err != nil {
+				return false, fmt.Errorf("unable to handle release %s: %w", r, err)
+			}
+		}
+	}
+	return changed, nil
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package packages
+
+import (
+	"fmt"
+
+	"golang.org/x/mod/semver"
+)
+
+// AlignmentStrategy picks which release of an Additional source should be
+// paired with a Primary release, out of candidates (every release of that
+// source, newest first). Strategies that only make sense within the
+// primary release's major.minor (TimeAligned, LatestMatchingMinor) must do
+// that filtering themselves; PinnedPatch and SemverConstraint intentionally
+// search the full, unfiltered list so they can pick a release from a
+// different minor than the primary's.
+type AlignmentStrategy interface {
+	Select(primary Release, candidates []Release) (Release, error)
+}
+
+// matchingMinor returns the subset of candidates (already sorted newest
+// first) sharing primary's major.minor.
+func matchingMinor(primary Release, candidates []Release) []Release {
+	majorMinor := semver.MajorMinor(primary.TagName)
+	start, end := -1, len(candidates)
+	for i, c := range candidates {
+		comp := semver.Compare(majorMinor, semver.MajorMinor(c.TagName))
+		if start == -1 && comp == 0 {
+			start = i
+		}
+		if comp > 0 {
+			end = i
+			break
+		}
+	}
+	if start == -1 {
+		return nil
+	}
+	return candidates[start:end]
+}
+
+// TimeAligned reproduces this package's original behaviour: the newest
+// candidate, of the primary release's major.minor, created before the
+// primary release, falling back to the oldest matching candidate if every
+// candidate is newer.
+type TimeAligned struct{}
+
+// Select implements AlignmentStrategy.
+func (TimeAligned) Select(primary Release, candidates []Release) (Release, error) {
+	candidates = matchingMinor(primary, candidates)
+	if len(candidates) == 0 {
+		return Release{}, fmt.Errorf("no candidates to align with %s", primary)
+	}
+	match := len(candidates) - 1
+	for i, c := range candidates {
+		if primary.Created.After(c.Created) {
+			match = i
+			break
+		}
+	}
+	return candidates[match], nil
+}
+
+// LatestMatchingMinor always selects the newest patch release of the
+// matching minor, regardless of either release's creation date. Useful
+// when a source's patch cadence should simply track the newest available,
+// e.g. a serving patch pulling the newest eventing patch of the same
+// minor even when eventing was cut later.
+type LatestMatchingMinor struct{}
+
+// Select implements AlignmentStrategy.
+func (LatestMatchingMinor) Select(primary Release, candidates []Release) (Release, error) {
+	candidates = matchingMinor(primary, candidates)
+	if len(candidates) == 0 {
+		return Release{}, fmt.Errorf("no candidates to align with %s", primary)
+	}
+	return candidates[0], nil
+}
+
+// PinnedPatch selects an explicit patch version for a given primary
+// TagName, e.g. {"v0.20.1": "v0.20.2"}.
+type PinnedPatch struct {
+	// Pins maps a primary release's TagName to the TagName it should be
+	// paired with.
+	Pins map[string]string
+}
+
+// Select implements AlignmentStrategy.
+func (p PinnedPatch) Select(primary Release, candidates []Release) (Release, error) {
+	want, ok := p.Pins[primary.TagName]
+	if !ok {
+		return Release{}, fmt.Errorf("no pin for %s", primary.TagName)
+	}
+	for _, c := range candidates {
+		if c.TagName == want {
+			return c, nil
+		}
+	}
+	return Release{}, fmt.Errorf("pinned version %s not found among candidates for %s", want, primary.TagName)
+}
+
+// SemverConstraint selects the newest candidate satisfying a user-supplied
+// semver constraint (e.g. "^0.20"), evaluated with golang.org/x/mod/semver.
+type SemverConstraint struct {
+	// Constraint is a "^v0.20.0"-style prefix constraint: a candidate
+	// satisfies it if its TagName shares the same major.minor.
+	Constraint string
+}
+
+// Select implements AlignmentStrategy.
+func (s SemverConstraint) Select(primary Release, candidates []Release) (Release, error) {
+	want := semver.MajorMinor(normalizeVersion(s.Constraint))
+	for _, c := range candidates {
+		if semver.MajorMinor(c.TagName) == want {
+			return c, nil
+		}
+	}
+	return Release{}, fmt.Errorf("no candidate satisfies constraint %q for %s", s.Constraint, primary.TagName)
+}
+
+// normalizeVersion strips a leading "^" from a constraint and ensures a
+// "v" prefix, so it can be passed to golang.org/x/mod/semver.
+func normalizeVersion(constraint string) string {
+	v := constraint
+	if len(v) > 0 && v[0] == '^' {
+		v = v[1:]
+	}
+	if len(v) > 0 && v[0] != 'v' {
+		v = "v" + v
+	}
+	return v
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package packages
+
+import "testing"
+
+// candidates spans two minors, as ResolveAssets now passes the full,
+// unfiltered list of a source's releases to every AlignmentStrategy.
+var crossMinorCandidates = []Release{
+	{TagName: "v0.21.0"},
+	{TagName: "v0.20.1"},
+	{TagName: "v0.20.0"},
+}
+
+func TestPinnedPatchSelectsAcrossMinors(t *testing.T) {
+	primary := Release{TagName: "v0.21.0"}
+	strategy := PinnedPatch{Pins: map[string]string{"v0.21.0": "v0.20.0"}}
+
+	got, err := strategy.Select(primary, crossMinorCandidates)
+	if err != nil {
+		t.Fatalf("Select() = %v, want nil", err)
+	}
+	if got.TagName != "v0.20.0" {
+		t.Errorf("Select() = %s, want v0.20.0", got.TagName)
+	}
+}
+
+func TestSemverConstraintSelectsAcrossMinors(t *testing.T) {
+	primary := Release{TagName: "v0.21.0"}
+	strategy := SemverConstraint{Constraint: "^0.20"}
+
+	got, err := strategy.Select(primary, crossMinorCandidates)
+	if err != nil {
+		t.Fatalf("Select() = %v, want nil", err)
+	}
+	if got.TagName != "v0.20.1" {
+		t.Errorf("Select() = %s, want v0.20.1", got.TagName)
+	}
+}
+
+func TestTimeAlignedOnlyConsidersMatchingMinor(t *testing.T) {
+	primary := Release{TagName: "v0.21.0"}
+	strategy := TimeAligned{}
+
+	_, err := strategy.Select(primary, []Release{
+		{TagName: "v0.20.1"},
+		{TagName: "v0.20.0"},
+	})
+	if err == nil {
+		t.Fatal("Select() = nil error, want error: no v0.21.x candidate available")
+	}
+}
@@ -0,0 +1,224 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package packages
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// DefaultCacheDir is where Downloader stores fetched assets, keyed by URL,
+// so that re-running the generator across many releases doesn't
+// redownload files (e.g. CRD YAMLs) shared between them.
+const DefaultCacheDir = "cmd/operator/kodata/.cache"
+
+// DefaultParallelism bounds how many assets Downloader fetches at once
+// when none is configured.
+const DefaultParallelism = 4
+
+// RangeSource is implemented by Sources that can resume an interrupted
+// download starting at a byte offset, by serving an HTTP Range request or
+// equivalent.
+type RangeSource interface {
+	FetchAssetRange(ctx context.Context, asset Asset, offset int64, w io.Writer) error
+}
+
+// Downloader fetches a set of Assets concurrently, retrying transient
+// failures with backoff and caching fetched content by URL so that
+// identical assets requested by different releases (or different runs)
+// are only downloaded once.
+type Downloader struct {
+	// Parallelism bounds the number of assets fetched concurrently.
+	// Defaults to DefaultParallelism.
+	Parallelism int
+	// CacheDir is the content-addressed store assets are fetched into
+	// before being copied to their destination. Defaults to
+	// DefaultCacheDir.
+	CacheDir string
+
+	// mu guards cacheLocks.
+	mu sync.Mutex
+	// cacheLocks holds one *sync.Mutex per cache path currently in use, so
+	// that two assets sharing a URL (and therefore a cache path) block on
+	// the first fetch instead of racing to write the same file.
+	cacheLocks map[string]*sync.Mutex
+}
+
+// NewDownloader returns a Downloader configured with this package's
+// defaults.
+func NewDownloader() *Downloader {
+	return &Downloader{
+		Parallelism: DefaultParallelism,
+		CacheDir:    DefaultCacheDir,
+	}
+}
+
+// Download fetches assets into destDir, naming each file
+// "<position>-<asset.Name>" to preserve the caller's intended apply order,
+// honoring ctx cancellation and stopping at the first error.
+func (d *Downloader) Download(ctx context.Context, assets []Asset, destDir string) error {
+	if d.Parallelism <= 0 {
+		d.Parallelism = DefaultParallelism
+	}
+	if d.CacheDir == "" {
+		d.CacheDir = DefaultCacheDir
+	}
+	if err := os.MkdirAll(d.CacheDir, 0755); err != nil {
+		return fmt.Errorf("unable to create cache dir %s: %w", d.CacheDir, err)
+	}
+
+	sem := make(chan struct{}, d.Parallelism)
+	g, ctx := errgroup.WithContext(ctx)
+	for i, asset := range assets {
+		i, asset := i, asset
+		destPath := filepath.Join(destDir, fmt.Sprintf("%d-%s", i+1, asset.Name))
+		g.Go(func() error {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			defer func() { <-sem }()
+			return d.downloadOne(ctx, asset, destPath)
+		})
+	}
+	return g.Wait()
+}
+
+// downloadOne fetches asset into the cache (resuming or reusing a prior
+// download when possible), verifies it, then copies it to destPath.
+func (d *Downloader) downloadOne(ctx context.Context, asset Asset, destPath string) error {
+	cachePath := d.cachePath(asset)
+
+	lock := d.lockFor(cachePath)
+	lock.Lock()
+	err := d.fetchToCache(ctx, asset, cachePath)
+	lock.Unlock()
+	if err != nil {
+		os.Remove(cachePath)
+		os.Remove(cachePath + ".done")
+		return err
+	}
+
+	if verifier := VerifierFor(asset); verifier != nil {
+		if err := verifier.Verify(ctx, asset, cachePath); err != nil {
+			os.Remove(cachePath)
+			os.Remove(cachePath + ".done")
+			return fmt.Errorf("rejecting %s: %w", asset.Name, err)
+		}
+	}
+
+	return copyFile(cachePath, destPath)
+}
+
+// cachePath returns the content-addressed path for asset, keyed by its
+// URL (which already encodes the resolved release/tag).
+func (d *Downloader) cachePath(asset Asset) string {
+	h := sha256.Sum256([]byte(asset.URL))
+	return filepath.Join(d.CacheDir, hex.EncodeToString(h[:]))
+}
+
+// lockFor returns the mutex serializing fetches of cachePath, creating one
+// on first use.
+func (d *Downloader) lockFor(cachePath string) *sync.Mutex {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.cacheLocks == nil {
+		d.cacheLocks = map[string]*sync.Mutex{}
+	}
+	l, ok := d.cacheLocks[cachePath]
+	if !ok {
+		l = &sync.Mutex{}
+		d.cacheLocks[cachePath] = l
+	}
+	return l
+}
+
+// fetchToCache downloads asset into cachePath, resuming a prior partial
+// download if the Source supports RangeSource. A sibling ".done" file
+// marks a cache entry as complete so it can be reused verbatim by later
+// calls or runs.
+func (d *Downloader) fetchToCache(ctx context.Context, asset Asset, cachePath string) error {
+	donePath := cachePath + ".done"
+	if _, err := os.Stat(donePath); err == nil {
+		return nil
+	}
+
+	var offset int64
+	if fi, err := os.Stat(cachePath); err == nil {
+		offset = fi.Size()
+	}
+
+	rs, resumable := asset.Source.(RangeSource)
+	if offset > 0 && !resumable {
+		// The Source can't resume; start over rather than risk corrupt
+		// output.
+		offset = 0
+		if err := os.Truncate(cachePath, 0); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("unable to reset partial download of %s: %w", asset.Name, err)
+		}
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY
+	if offset > 0 {
+		flags |= os.O_APPEND
+	} else {
+		flags |= os.O_TRUNC
+	}
+	file, err := os.OpenFile(cachePath, flags, 0644)
+	if err != nil {
+		return fmt.Errorf("unable to open cache file for %s: %w", asset.Name, err)
+	}
+	defer file.Close()
+
+	if offset > 0 && resumable {
+		if err := rs.FetchAssetRange(ctx, asset, offset, file); err != nil {
+			return fmt.Errorf("unable to resume %s: %w", asset.Name, err)
+		}
+	} else if err := asset.Source.FetchAsset(ctx, asset, file); err != nil {
+		return fmt.Errorf("unable to fetch %s: %w", asset.Name, err)
+	}
+
+	return os.WriteFile(donePath, nil, 0644)
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("unable to open %s: %w", src, err)
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("unable to open %s: %w", dst, err)
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, in); err != nil {
+		return fmt.Errorf("unable to write %s: %w", dst, err)
+	}
+	return nil
+}
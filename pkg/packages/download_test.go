@@ -0,0 +1,109 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package packages
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+// countingSource fetches a fixed body and tracks how many times
+// FetchAsset was actually invoked, so tests can assert the cache avoided
+// redundant fetches.
+type countingSource struct {
+	body  string
+	calls int32
+}
+
+func (s *countingSource) ListReleases(ctx context.Context, org, repo string) ([]Release, error) {
+	return nil, nil
+}
+
+func (s *countingSource) FetchAsset(ctx context.Context, asset Asset, w io.Writer) error {
+	atomic.AddInt32(&s.calls, 1)
+	_, err := io.WriteString(w, s.body)
+	return err
+}
+
+func TestDownloaderCachesByURL(t *testing.T) {
+	dir := t.TempDir()
+	src := &countingSource{body: "hello"}
+	d := &Downloader{Parallelism: 2, CacheDir: filepath.Join(dir, "cache")}
+
+	assets := []Asset{
+		{Name: "a.yaml", URL: "http://example.com/shared.yaml", Source: src},
+		{Name: "b.yaml", URL: "http://example.com/shared.yaml", Source: src},
+	}
+
+	destDir := filepath.Join(dir, "dest")
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Download(context.Background(), assets, destDir); err != nil {
+		t.Fatalf("Download() = %v, want nil", err)
+	}
+
+	for i, asset := range assets {
+		fileName := fmt.Sprintf("%d-%s", i+1, asset.Name)
+		data, err := os.ReadFile(filepath.Join(destDir, fileName))
+		if err != nil {
+			t.Fatalf("reading downloaded %s: %v", asset.Name, err)
+		}
+		if string(data) != src.body {
+			t.Errorf("downloaded content = %q, want %q", data, src.body)
+		}
+	}
+
+	if got := atomic.LoadInt32(&src.calls); got != 1 {
+		t.Errorf("FetchAsset called %d times for two assets sharing a URL, want 1 (cache hit)", got)
+	}
+}
+
+func TestDownloadVerificationFailureRemovesCacheEntry(t *testing.T) {
+	dir := t.TempDir()
+	src := &countingSource{body: "hello"}
+	cacheDir := filepath.Join(dir, "cache")
+	d := &Downloader{Parallelism: 1, CacheDir: cacheDir}
+
+	asset := Asset{
+		Name:         "asset.yaml",
+		URL:          "http://example.com/asset.yaml",
+		Source:       src,
+		Verification: Verification{SHA256: "0000000000000000000000000000000000000000000000000000000000000000"},
+	}
+
+	destDir := filepath.Join(dir, "dest")
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Download(context.Background(), []Asset{asset}, destDir); err == nil {
+		t.Fatal("Download() = nil, want a verification error")
+	}
+
+	cachePath := d.cachePath(asset)
+	if _, err := os.Stat(cachePath); !os.IsNotExist(err) {
+		t.Errorf("cache file %s still exists after a failed verification, want it removed", cachePath)
+	}
+	if _, err := os.Stat(cachePath + ".done"); !os.IsNotExist(err) {
+		t.Errorf("cache marker %s.done still exists after a failed verification, want it removed", cachePath)
+	}
+}
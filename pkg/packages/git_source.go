@@ -0,0 +1,107 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package packages
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// GitSource implements Source against a plain git remote, for projects
+// that ship their install manifests as files in-tree rather than as
+// GitHub release assets. Releases correspond to tags, and Assets to
+// specific paths requested at FetchAsset time (asset.URL is
+// "<path>@<ref>").
+type GitSource struct {
+	// RemoteURL is the git remote to clone tags and blobs from.
+	RemoteURL string
+}
+
+// ListReleases implements Source by listing the remote's tags.
+func (s *GitSource) ListReleases(ctx context.Context, org, repo string) ([]Release, error) {
+	out, err := exec.CommandContext(ctx, "git", "ls-remote", "--tags", "--refs", s.RemoteURL).Output()
+	if err != nil {
+		return nil, fmt.Errorf("unable to list tags for %s: %w", s.RemoteURL, err)
+	}
+	var releases []Release
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if line == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		tag := strings.TrimPrefix(fields[1], "refs/tags/")
+		releases = append(releases, Release{Org: org, Repo: repo, TagName: tag})
+	}
+	return releases, nil
+}
+
+// FetchAsset implements Source by running `git archive --remote=...` for
+// path against RemoteURL, a clone-less way to fetch a single blob, then
+// unpacking the one regular-file entry it contains to w.
+func (s *GitSource) FetchAsset(ctx context.Context, asset Asset, w io.Writer) error {
+	path, ref, ok := splitGitAssetURL(asset.URL)
+	if !ok {
+		return fmt.Errorf("Unable to parse git asset reference %q, want \"<path>@<ref>\"", asset.URL)
+	}
+	var buf bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", "archive", "--remote="+s.RemoteURL, ref, path)
+	cmd.Stdout = &buf
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("Unable to fetch %s: %w", asset.URL, err)
+	}
+
+	if err := unpackSingleTarEntry(&buf, w); err != nil {
+		return fmt.Errorf("Unable to read git archive for %s: %w", asset.URL, err)
+	}
+	return nil
+}
+
+// unpackSingleTarEntry copies the contents of the first regular-file entry
+// in the tar stream r to w.
+func unpackSingleTarEntry(r io.Reader, w io.Writer) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return fmt.Errorf("archive contained no entries")
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		_, err = io.Copy(w, tr)
+		return err
+	}
+}
+
+func splitGitAssetURL(url string) (path, ref string, ok bool) {
+	i := strings.LastIndex(url, "@")
+	if i < 0 {
+		return "", "", false
+	}
+	return url[:i], url[i+1:], true
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package packages
+
+import (
+	"archive/tar"
+	"bytes"
+	"testing"
+)
+
+func TestSplitGitAssetURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		path string
+		ref  string
+		ok   bool
+	}{
+		{"config/serving-crds.yaml@v0.20.0", "config/serving-crds.yaml", "v0.20.0", true},
+		{"no-at-sign", "", "", false},
+	}
+	for _, test := range tests {
+		path, ref, ok := splitGitAssetURL(test.url)
+		if path != test.path || ref != test.ref || ok != test.ok {
+			t.Errorf("splitGitAssetURL(%q) = (%q, %q, %v), want (%q, %q, %v)",
+				test.url, path, ref, ok, test.path, test.ref, test.ok)
+		}
+	}
+}
+
+func TestUnpackSingleTarEntry(t *testing.T) {
+	var archive bytes.Buffer
+	tw := tar.NewWriter(&archive)
+	content := []byte("apiVersion: v1\nkind: ConfigMap\n")
+	if err := tw.WriteHeader(&tar.Header{Name: "config/serving-crds.yaml", Size: int64(len(content))}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write(content); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := unpackSingleTarEntry(&archive, &out); err != nil {
+		t.Fatalf("unpackSingleTarEntry() = %v, want nil", err)
+	}
+	if out.String() != string(content) {
+		t.Errorf("unpackSingleTarEntry() wrote %q, want %q", out.String(), content)
+	}
+}
+
+func TestUnpackSingleTarEntryEmpty(t *testing.T) {
+	var archive bytes.Buffer
+	if err := tar.NewWriter(&archive).Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := unpackSingleTarEntry(&archive, &out); err == nil {
+		t.Error("unpackSingleTarEntry() on an empty archive = nil, want an error")
+	}
+}
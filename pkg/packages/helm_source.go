@@ -0,0 +1,107 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package packages
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+// helmIndex is the subset of a Helm repository's index.yaml this source
+// needs.
+type helmIndex struct {
+	Entries map[string][]struct {
+		Version string   `json:"version"`
+		URLs    []string `json:"urls"`
+		Created string   `json:"created"`
+	} `json:"entries"`
+}
+
+// HelmSource implements Source against a Helm chart repository, treating
+// each chart version as a Release and the packaged chart as its single
+// Asset.
+type HelmSource struct {
+	Client  *http.Client
+	RepoURL string
+}
+
+// ListReleases implements Source by fetching and parsing the repository's
+// index.yaml. The chart name is taken from repo, org is ignored.
+func (s *HelmSource) ListReleases(ctx context.Context, org, repo string) ([]Release, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.RepoURL+"/index.yaml", nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to build request for %s: %w", s.RepoURL, err)
+	}
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch %s/index.yaml: %w", s.RepoURL, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s/index.yaml: %w", s.RepoURL, err)
+	}
+	var idx helmIndex
+	if err := yaml.Unmarshal(body, &idx); err != nil {
+		return nil, fmt.Errorf("unable to parse %s/index.yaml: %w", s.RepoURL, err)
+	}
+	versions, ok := idx.Entries[repo]
+	if !ok {
+		return nil, fmt.Errorf("no chart %q in %s/index.yaml", repo, s.RepoURL)
+	}
+	releases := make([]Release, 0, len(versions))
+	for _, v := range versions {
+		if len(v.URLs) == 0 {
+			continue
+		}
+		created, err := time.Parse(time.RFC3339, v.Created)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse created time %q for %s %s: %w", v.Created, repo, v.Version, err)
+		}
+		releases = append(releases, Release{
+			Org:     org,
+			Repo:    repo,
+			TagName: "v" + v.Version,
+			Created: created,
+			Assets: assetList{{
+				Name: fmt.Sprintf("%s-%s.tgz", repo, v.Version),
+				URL:  v.URLs[0],
+			}},
+		})
+	}
+	return releases, nil
+}
+
+// FetchAsset implements Source.
+func (s *HelmSource) FetchAsset(ctx context.Context, asset Asset, w io.Writer) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, asset.URL, nil)
+	if err != nil {
+		return fmt.Errorf("Unable to build request for %s: %w", asset.URL, err)
+	}
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Unable to fetch %s: %w", asset.URL, err)
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
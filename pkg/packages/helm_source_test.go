@@ -0,0 +1,52 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package packages
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHelmSourceListReleasesParsesCreated(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`
+entries:
+  knative-operator:
+    - version: 1.2.3
+      urls: ["https://example.com/knative-operator-1.2.3.tgz"]
+      created: "2021-03-04T15:04:05Z"
+`))
+	}))
+	defer server.Close()
+
+	s := &HelmSource{Client: server.Client(), RepoURL: server.URL}
+	releases, err := s.ListReleases(context.Background(), "knative", "knative-operator")
+	if err != nil {
+		t.Fatalf("ListReleases() = %v, want nil error", err)
+	}
+	if len(releases) != 1 {
+		t.Fatalf("ListReleases() returned %d releases, want 1", len(releases))
+	}
+
+	want := time.Date(2021, 3, 4, 15, 4, 5, 0, time.UTC)
+	if !releases[0].Created.Equal(want) {
+		t.Errorf("releases[0].Created = %v, want %v", releases[0].Created, want)
+	}
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package packages
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLockUnchanged(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "1-a.yaml"), []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	size, sum, err := hashFile(filepath.Join(dir, "1-a.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resolved := map[string]string{"github:a/b": "v1.0.0"}
+	assets := assetList{{Name: "a.yaml", URL: "http://example.com/a.yaml"}}
+	lock := &Lock{
+		Resolved: resolved,
+		Assets: []LockedAsset{
+			{Name: "a.yaml", URL: "http://example.com/a.yaml", Size: size, SHA256: sum},
+		},
+	}
+
+	if !lock.Unchanged(resolved, assets, dir) {
+		t.Error("Unchanged() = false, want true for an identical lock")
+	}
+
+	if (*Lock)(nil).Unchanged(resolved, assets, dir) {
+		t.Error("Unchanged() = true for a nil lock, want false")
+	}
+
+	changedResolved := map[string]string{"github:a/b": "v1.0.1"}
+	if lock.Unchanged(changedResolved, assets, dir) {
+		t.Error("Unchanged() = true despite a different resolved version, want false")
+	}
+
+	changedURL := assetList{{Name: "a.yaml", URL: "http://example.com/a-new.yaml"}}
+	if lock.Unchanged(resolved, changedURL, dir) {
+		t.Error("Unchanged() = true despite a different asset URL, want false")
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "1-a.yaml"), []byte("modified"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if lock.Unchanged(resolved, assets, dir) {
+		t.Error("Unchanged() = true despite the on-disk file no longer matching the recorded SHA256, want false")
+	}
+}
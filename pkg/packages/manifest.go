@@ -0,0 +1,221 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package packages
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Manifest is the top-level packages.yaml schema: a declarative
+// description of the packages, sources, and rules that were previously
+// only expressible in Go code.
+type Manifest struct {
+	Packages []PackageManifest `json:"packages"`
+}
+
+// PackageManifest describes a single Package in packages.yaml.
+type PackageManifest struct {
+	Name       string         `json:"name"`
+	Primary    SourceManifest `json:"primary"`
+	Additional []SourceManifest `json:"additional,omitempty"`
+	// Order lists filename suffixes in the order assets should be
+	// applied; see DefaultOrder. Omit to use DefaultOrder.
+	Order []string `json:"order,omitempty"`
+}
+
+// SourceManifest describes one Source entry (a Package's Primary or one
+// of its Additional sources) in packages.yaml.
+type SourceManifest struct {
+	// Type selects the Source implementation: "github", "oci", "helm",
+	// "git", or "http".
+	Type string `json:"type"`
+	Org  string `json:"org,omitempty"`
+	Repo string `json:"repo,omitempty"`
+
+	// Repository is used by the "oci" type.
+	Repository string `json:"repository,omitempty"`
+	// RepoURL is used by the "helm" type.
+	RepoURL string `json:"repoURL,omitempty"`
+	// RemoteURL is used by the "git" type.
+	RemoteURL string `json:"remoteURL,omitempty"`
+	// BaseURL is used by the "http" type.
+	BaseURL string `json:"baseURL,omitempty"`
+
+	// Rename lists regex-based rules, evaluated in order, used to build
+	// this source's Accept function. An asset matching no rule is
+	// accepted unchanged.
+	Rename []RenameRule `json:"rename,omitempty"`
+
+	// Alignment selects how a release of this source is paired with a
+	// package's primary release, when this entry is one of a package's
+	// Additional sources. Defaults to "time".
+	Alignment *AlignmentManifest `json:"alignment,omitempty"`
+}
+
+// AlignmentManifest is the YAML equivalent of an AlignmentStrategy.
+type AlignmentManifest struct {
+	// Type selects the strategy: "time" (default), "latestMinor",
+	// "pinned", or "semver".
+	Type string `json:"type"`
+	// Pins is used by the "pinned" type: primary TagName -> source TagName.
+	Pins map[string]string `json:"pins,omitempty"`
+	// Constraint is used by the "semver" type, e.g. "^0.20".
+	Constraint string `json:"constraint,omitempty"`
+}
+
+func (am *AlignmentManifest) toAlignmentStrategy() (AlignmentStrategy, error) {
+	if am == nil {
+		return nil, nil
+	}
+	switch am.Type {
+	case "time", "":
+		return TimeAligned{}, nil
+	case "latestMinor":
+		return LatestMatchingMinor{}, nil
+	case "pinned":
+		return PinnedPatch{Pins: am.Pins}, nil
+	case "semver":
+		return SemverConstraint{Constraint: am.Constraint}, nil
+	default:
+		return nil, fmt.Errorf("unknown alignment type %q", am.Type)
+	}
+}
+
+// RenameRule is the YAML equivalent of an Accept function: Match is a
+// regular expression evaluated against an asset's name. If it matches and
+// Drop is set, the asset is excluded. If it matches and Replace is set,
+// the name is rewritten via Match.ReplaceAllString(name, Replace).
+type RenameRule struct {
+	Match   string `json:"match"`
+	Drop    bool   `json:"drop,omitempty"`
+	Replace string `json:"replace,omitempty"`
+}
+
+// LoadManifest parses a packages.yaml document.
+func LoadManifest(data []byte) (*Manifest, error) {
+	var m Manifest
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("unable to parse packages manifest: %w", err)
+	}
+	return &m, nil
+}
+
+// BuildPackages builds the runtime Package values described by the
+// manifest, using httpClient for any Source that needs to make HTTP
+// requests.
+func (m *Manifest) BuildPackages(httpClient *http.Client) ([]Package, error) {
+	packages := make([]Package, 0, len(m.Packages))
+	for _, pm := range m.Packages {
+		p := Package{Name: pm.Name, Order: pm.Order}
+
+		primary, err := pm.Primary.toPackageSource(httpClient)
+		if err != nil {
+			return nil, fmt.Errorf("package %s: primary source: %w", pm.Name, err)
+		}
+		p.Primary = primary
+
+		for i, sm := range pm.Additional {
+			src, err := sm.toPackageSource(httpClient)
+			if err != nil {
+				return nil, fmt.Errorf("package %s: additional source %d: %w", pm.Name, i, err)
+			}
+			p.Additional = append(p.Additional, src)
+		}
+
+		packages = append(packages, p)
+	}
+	return packages, nil
+}
+
+func (sm SourceManifest) toPackageSource(httpClient *http.Client) (PackageSource, error) {
+	ps := PackageSource{Org: sm.Org, Repo: sm.Repo}
+
+	switch sm.Type {
+	case "github", "":
+		ps.Source = NewGitHubSource(httpClient)
+		ps.Key = fmt.Sprintf("github:%s/%s", sm.Org, sm.Repo)
+	case "oci":
+		ps.Source = &OCISource{Repository: sm.Repository}
+		ps.Key = "oci:" + sm.Repository
+	case "helm":
+		ps.Source = &HelmSource{Client: httpClient, RepoURL: sm.RepoURL}
+		ps.Key = fmt.Sprintf("helm:%s/%s", sm.RepoURL, sm.Repo)
+	case "git":
+		ps.Source = &GitSource{RemoteURL: sm.RemoteURL}
+		ps.Key = "git:" + sm.RemoteURL
+	case "http":
+		ps.Source = NewHTTPSource(sm.BaseURL, httpClient)
+		ps.Key = "http:" + sm.BaseURL
+	default:
+		return PackageSource{}, fmt.Errorf("unknown source type %q", sm.Type)
+	}
+
+	rename, err := compileRenameRules(sm.Rename)
+	if err != nil {
+		return PackageSource{}, err
+	}
+	ps.Rename = rename
+
+	alignment, err := sm.Alignment.toAlignmentStrategy()
+	if err != nil {
+		return PackageSource{}, err
+	}
+	ps.Alignment = alignment
+
+	return ps, nil
+}
+
+// compileRenameRules compiles rules once and returns a PackageSource.Rename
+// function that applies them in order for every release tag.
+func compileRenameRules(rules []RenameRule) (func(string) func(string) string, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	type compiled struct {
+		match   *regexp.Regexp
+		drop    bool
+		replace string
+	}
+	compiledRules := make([]compiled, 0, len(rules))
+	for _, r := range rules {
+		re, err := regexp.Compile(r.Match)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rename rule %q: %w", r.Match, err)
+		}
+		compiledRules = append(compiledRules, compiled{match: re, drop: r.Drop, replace: r.Replace})
+	}
+
+	return func(tagName string) func(string) string {
+		return func(name string) string {
+			for _, r := range compiledRules {
+				if !r.match.MatchString(name) {
+					continue
+				}
+				if r.drop {
+					return ""
+				}
+				return r.match.ReplaceAllString(name, r.replace)
+			}
+			return name
+		}
+	}, nil
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package packages
+
+import "testing"
+
+func TestBuildPackagesOCISourcesHaveDistinctKeys(t *testing.T) {
+	m, err := LoadManifest([]byte(`
+packages:
+  - name: a
+    primary:
+      type: oci
+      repository: gcr.io/example/a
+  - name: b
+    primary:
+      type: oci
+      repository: gcr.io/example/b
+`))
+	if err != nil {
+		t.Fatalf("LoadManifest() = %v, want nil", err)
+	}
+
+	pkgs, err := m.BuildPackages(nil)
+	if err != nil {
+		t.Fatalf("BuildPackages() = %v, want nil", err)
+	}
+	if len(pkgs) != 2 {
+		t.Fatalf("BuildPackages() returned %d packages, want 2", len(pkgs))
+	}
+
+	keyA, keyB := pkgs[0].Primary.String(), pkgs[1].Primary.String()
+	if keyA == keyB {
+		t.Errorf("two distinct OCI sources both resolved to key %q, want distinct keys", keyA)
+	}
+}
+
+func TestCompileRenameRules(t *testing.T) {
+	accept, err := compileRenameRules([]RenameRule{
+		{Match: "README|LICENSE", Drop: true},
+		{Match: `^(serving)-core\.yaml$`, Replace: "${1}.yaml"},
+	})
+	if err != nil {
+		t.Fatalf("compileRenameRules() = %v, want nil", err)
+	}
+	fn := accept("v1.0.0")
+
+	if got := fn("README.md"); got != "" {
+		t.Errorf("fn(README.md) = %q, want dropped", got)
+	}
+	if got := fn("serving-core.yaml"); got != "serving.yaml" {
+		t.Errorf("fn(serving-core.yaml) = %q, want serving.yaml", got)
+	}
+	if got := fn("serving-crds.yaml"); got != "serving-crds.yaml" {
+		t.Errorf("fn(serving-crds.yaml) = %q, want unchanged", got)
+	}
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package packages
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+// OCISource implements Source against an OCI artifact registry, pulling
+// manifest YAMLs that have been packaged as OCI artifacts (one file per
+// layer) the same way Bazel's rules_docker puller fetches image layers.
+// Releases are addressed by tag rather than discovered, since most OCI
+// registries don't expose a "list releases" concept.
+type OCISource struct {
+	// Repository is the registry repository containing tagged artifacts,
+	// e.g. "gcr.io/knative-releases/serving".
+	Repository string
+}
+
+// ListReleases implements Source by listing the tags of Repository and
+// treating each as a Release whose Assets are that tag's layers.
+func (s *OCISource) ListReleases(ctx context.Context, org, repo string) ([]Release, error) {
+	tags, err := crane.ListTags(s.Repository)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list tags for %s: %w", s.Repository, err)
+	}
+	releases := make([]Release, 0, len(tags))
+	for _, tag := range tags {
+		ref := fmt.Sprintf("%s:%s", s.Repository, tag)
+		img, err := crane.Pull(ref)
+		if err != nil {
+			return nil, fmt.Errorf("unable to pull %s: %w", ref, err)
+		}
+		manifest, err := img.Manifest()
+		if err != nil {
+			return nil, fmt.Errorf("unable to read manifest for %s: %w", ref, err)
+		}
+		assets := make(assetList, 0, len(manifest.Layers))
+		for _, layer := range manifest.Layers {
+			assets = append(assets, Asset{
+				Name: layer.Annotations["org.opencontainers.image.title"],
+				URL:  fmt.Sprintf("%s@%s", s.Repository, layer.Digest.String()),
+			})
+		}
+		releases = append(releases, Release{
+			Org:     org,
+			Repo:    repo,
+			TagName: tag,
+			Assets:  assets,
+		})
+	}
+	return releases, nil
+}
+
+// FetchAsset implements Source by pulling the layer referenced by
+// asset.URL (a "repository@digest" reference) and streaming its
+// uncompressed contents to w.
+func (s *OCISource) FetchAsset(ctx context.Context, asset Asset, w io.Writer) error {
+	ref, err := name.NewDigest(asset.URL)
+	if err != nil {
+		return fmt.Errorf("Unable to parse digest %s: %w", asset.URL, err)
+	}
+	layer, err := crane.PullLayer(ref.String())
+	if err != nil {
+		return fmt.Errorf("Unable to pull layer %s: %w", asset.URL, err)
+	}
+	rc, err := layer.Uncompressed()
+	if err != nil {
+		return fmt.Errorf("Unable to read layer %s: %w", asset.URL, err)
+	}
+	defer rc.Close()
+	_, err = io.Copy(w, rc)
+	return err
+}
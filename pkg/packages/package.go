@@ -0,0 +1,75 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package packages
+
+import "fmt"
+
+// Package describes a single component (e.g. "serving" or "eventing")
+// whose release assets should be fetched and stored under
+// cmd/operator/kodata.
+type Package struct {
+	Name       string
+	Primary    PackageSource
+	Additional []PackageSource
+	// Order lists filename suffixes in the order assets should be
+	// applied; see DefaultOrder for its format and default value.
+	Order []string
+}
+
+// PackageSource binds a Source implementation to the coordinates it should
+// fetch from (e.g. a GitHub org/repo, an OCI repository, a Helm repo URL),
+// along with the rename/filter rules applied to the assets it returns.
+type PackageSource struct {
+	// Source is the backend used to list releases and fetch assets.
+	Source Source
+	// Org and Repo identify the upstream project within Source. Not every
+	// Source makes use of both fields.
+	Org, Repo string
+	// Key uniquely identifies this source for use as the allReleases/
+	// Lock.Resolved map key, e.g. "oci:gcr.io/knative-releases/serving".
+	// If empty, String falls back to "Org/Repo", which is only unique
+	// across a manifest when every source is GitHub-backed.
+	Key string
+	// Rename returns, for a given release tag, the accept function passed
+	// to assetList.FilterAssets. A nil Rename accepts every asset
+	// unmodified.
+	Rename func(tagName string) func(string) string
+	// Alignment picks which release of this source to pair with a
+	// Package's Primary release, when this PackageSource is one of its
+	// Additional entries. A nil Alignment uses TimeAligned.
+	Alignment AlignmentStrategy
+}
+
+// String identifies this source, and is used as the key into the
+// allReleases map passed to HandleRelease and into Lock.Resolved. It
+// returns Key if set, falling back to "Org/Repo" for sources constructed
+// without one.
+func (ps PackageSource) String() string {
+	if ps.Key != "" {
+		return ps.Key
+	}
+	return fmt.Sprintf("%s/%s", ps.Org, ps.Repo)
+}
+
+// Accept returns the accept/rename function used to filter and rename
+// assets belonging to the release tagged tagName.
+func (ps PackageSource) Accept(tagName string) func(string) string {
+	if ps.Rename == nil {
+		return func(name string) string { return name }
+	}
+	return ps.Rename(tagName)
+}
@@ -20,9 +20,7 @@ package packages
 import (
 	"context"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
 	"os"
 	"path/filepath"
 	"sort"
@@ -34,9 +32,54 @@ import (
 
 // Asset provides an abstract interface for describing a resource which should be stored on the disk.
 type Asset struct {
-	Name      string
-	URL       string
-	secondary bool
+	Name string
+	URL  string
+	// Source is the backend that produced this asset and that FetchAsset
+	// should be called on to retrieve its contents. It is populated by
+	// FilterAssets and need not be set by callers constructing Assets by
+	// hand for a single Source.
+	Source Source
+	// Verification, if non-zero, is checked against the downloaded file
+	// before it is accepted.
+	Verification Verification
+	secondary    bool
+	// order is the apply-ordering hint list in effect for this asset
+	// (usually its owning Package's Order). A nil order falls back to
+	// DefaultOrder.
+	order []string
+	// sourceTag is the TagName of the Release this asset was filtered
+	// from, recorded in Lock to show provenance.
+	sourceTag string
+}
+
+// DefaultOrder is the apply-ordering used when a Package doesn't specify
+// its own Order: pre-install jobs first, then CRDs, then everything else
+// (alphabetically), then the eventing sugar controller, then post-install
+// jobs. "*" stands in for "everything else".
+var DefaultOrder = []string{
+	"-pre-install-jobs.yaml",
+	"-crds.yaml",
+	"*",
+	"-sugar-controller.yaml",
+	"-post-install-jobs.yaml",
+}
+
+// orderRank returns the position in order that name's apply-ordering
+// group falls into, matching by filename suffix and falling back to the
+// "*" entry (or the end of order, if there is no "*") for anything that
+// doesn't match a more specific entry.
+func orderRank(order []string, name string) int {
+	wildcard := len(order)
+	for i, suffix := range order {
+		if suffix == "*" {
+			wildcard = i
+			continue
+		}
+		if strings.HasSuffix(name, suffix) {
+			return i
+		}
+	}
+	return wildcard
 }
 
 // Release provides an interface for a release which contains multiple assets at the same release (TagName)
@@ -49,38 +92,18 @@ type Release struct {
 }
 
 // Less provides a method for implementing `sort.Slice` to ensure that assets
-// are applied in the correct order.
+// are applied in the correct order, per their order (or DefaultOrder).
 func (a Asset) Less(b Asset) bool {
-	// HACK for pre-install jobs, which are deprecated, because the job needs to
-	// *complete*, not just be applied, before the next manifests can be
-	// applied.
-	if strings.HasSuffix(a.Name, "-pre-install-jobs.yaml") {
-		return true
-	}
-	if strings.HasSuffix(b.Name, "-pre-install-jobs.yaml") {
-		return false
+	order := a.order
+	if order == nil {
+		order = b.order
 	}
-
-	if strings.HasSuffix(a.Name, "-crds.yaml") {
-		return true
-	}
-	if strings.HasSuffix(b.Name, "-crds.yaml") {
-		return false
-	}
-	if strings.HasSuffix(a.Name, "-post-install-jobs.yaml") {
-		return false
-	}
-	if strings.HasSuffix(b.Name, "-post-install-jobs.yaml") {
-		return true
+	if order == nil {
+		order = DefaultOrder
 	}
 
-	// HACK for eventing, which lists the sugar controller after the
-	// channel/broker despite collating before.
-	if strings.HasSuffix(a.Name, "-sugar-controller.yaml") {
-		return false
-	}
-	if strings.HasSuffix(b.Name, "-sugar-controller.yaml") {
-		return true
+	if ra, rb := orderRank(order, a.Name), orderRank(order, b.Name); ra != rb {
+		return ra < rb
 	}
 	if a.secondary != b.secondary {
 		// Sort primary assets before secondary assets
@@ -149,75 +172,126 @@ func (al assetList) FilterAssets(accept func(string) string) assetList {
 	return retval
 }
 
-// HandleRelease processes the files for a given release of the specified
-// Package.
-func HandleRelease(ctx context.Context, client *http.Client, p Package, r Release, allReleases map[string][]Release) error {
-	majorMinor := semver.MajorMinor(r.TagName)
-	shortName := strings.TrimPrefix(r.TagName, "v")
-	path := filepath.Join("cmd", "operator", "kodata", p.Name, shortName)
-	err := os.MkdirAll(path, 0755)
-	if err != nil {
-		return err
-	}
+// releaseShortName returns r's TagName without its "v" prefix, as used for
+// its kodata directory name.
+func releaseShortName(r Release) string {
+	return strings.TrimPrefix(r.TagName, "v")
+}
+
+// ResolveAssets determines, for release r of Package p, the final sorted
+// list of assets to download along with the TagName each source (Primary
+// included, keyed by PackageSource.String()) resolved to. It does no I/O
+// other than the already-fetched allReleases, so it's cheap enough to call
+// from a --check path that never downloads anything.
+func ResolveAssets(p Package, r Release, allReleases map[string][]Release) (assetList, map[string]string, error) {
+	resolved := map[string]string{p.Primary.String(): r.TagName}
 
 	// TODO: make a copy of r's assets to avoid modifying the global cache.
 	assets := make(assetList, 0, len(r.Assets))
-	assets = append(assets, r.Assets.FilterAssets(p.Primary.Accept(r.TagName))...)
+	primaryAssets := r.Assets.FilterAssets(p.Primary.Accept(r.TagName))
+	for i := range primaryAssets {
+		primaryAssets[i].Source = p.Primary.Source
+		primaryAssets[i].order = p.Order
+		primaryAssets[i].sourceTag = r.TagName
+	}
+	assets = append(assets, primaryAssets...)
 	for _, src := range p.Additional {
 		candidates := allReleases[src.String()]
 		sort.Sort(releaseList(candidates))
-		start, end := -1, len(candidates)
-		for i, srcRelease := range candidates {
-			// Collect matching minor versions
-			comp := semver.Compare(majorMinor, semver.MajorMinor(srcRelease.TagName))
-			if start == -1 && comp == 0 {
-				start = i
-			}
-			if comp > 0 {
-				end = i
-				break
-			}
+
+		alignment := src.Alignment
+		if alignment == nil {
+			alignment = TimeAligned{}
 		}
-		candidates = candidates[start:end]
-		timeMatch := len(candidates) - 1
-		for i, srcRelease := range candidates {
-			// TODO: more sophisticated alignment options, for example, always use latest matching minor.
-			if r.Created.After(srcRelease.Created) {
-				timeMatch = i
-				break
-			}
+		candidate, err := alignment.Select(r, candidates)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to align %s with %s: %w", src.String(), r, err)
 		}
-
-		candidate := candidates[timeMatch]
 		newAssets := candidate.Assets.FilterAssets(src.Accept(candidate.TagName))
 		for i := range newAssets {
 			newAssets[i].secondary = true
+			newAssets[i].Source = src.Source
+			newAssets[i].order = p.Order
+			newAssets[i].sourceTag = candidate.TagName
 		}
 		assets = append(assets, newAssets...)
+		resolved[src.String()] = candidate.TagName
 		log.Printf("Using %s/%s with %s/%s", candidate.String(), candidate.TagName, r.String(), r.TagName)
 	}
 	sort.Sort(assets)
 
-	// Download assets and store them.
-	for i, asset := range assets {
-		fileName := fmt.Sprintf("%d-%s", i+1, asset.Name)
-		file, err := os.OpenFile(filepath.Join(path, fileName), os.O_CREATE|os.O_WRONLY, 0644)
-		if err != nil {
-			return fmt.Errorf("Unable to open %s: %w", fileName, err)
-		}
-		defer file.Close()
-		log.Print(asset.URL)
-		fetch, err := client.Get(asset.URL)
-		if err != nil {
-			return fmt.Errorf("Unable to fetch %s: %w", fileName, err)
-		}
-		defer fetch.Body.Close()
-		_, err = io.Copy(file, fetch.Body)
-		if err != nil {
-			return fmt.Errorf("Unable to write to %s: %w", fileName, err)
-		}
+	return assets, resolved, nil
+}
+
+// HandleRelease processes the files for a given release of the specified
+// Package. If d is nil, a Downloader with this package's defaults is used.
+// If the existing manifest.lock already matches what would be resolved and
+// downloaded (see Lock.Unchanged), the download is skipped entirely. On
+// success it writes a manifest.lock recording what was resolved and
+// downloaded, logging a diff against any prior lock.
+func HandleRelease(ctx context.Context, d *Downloader, p Package, r Release, allReleases map[string][]Release) error {
+	if d == nil {
+		d = NewDownloader()
+	}
+
+	assets, resolved, err := ResolveAssets(p, r, allReleases)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join("cmd", "operator", "kodata", p.Name, releaseShortName(r))
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return err
+	}
+
+	lockFile := filepath.Join(path, LockFileName)
+	oldLock, err := ReadLock(lockFile)
+	if err != nil {
+		return err
+	}
+	var oldResolved map[string]string
+	if oldLock != nil {
+		oldResolved = oldLock.Resolved
+	}
+	for _, line := range DiffResolved(oldResolved, resolved) {
+		log.Print(line)
+	}
+
+	if oldLock.Unchanged(resolved, assets, path) {
+		log.Printf("%s: lock unchanged, skipping download", r)
+		return nil
+	}
+
+	if err := d.Download(ctx, assets, path); err != nil {
+		return err
+	}
+
+	lock, err := buildLock(p, r, assets, resolved, path)
+	if err != nil {
+		return err
+	}
+	return WriteLock(lockFile, lock)
+}
+
+// CheckRelease reports, without downloading or writing anything, how
+// regenerating release r would change its manifest.lock's resolved
+// versions. An empty result means regeneration would be a no-op for
+// --check purposes.
+func CheckRelease(p Package, r Release, allReleases map[string][]Release) ([]string, error) {
+	_, resolved, err := ResolveAssets(p, r, allReleases)
+	if err != nil {
+		return nil, err
+	}
+
+	oldLock, err := ReadLock(lockPath(p, r))
+	if err != nil {
+		return nil, err
+	}
+	var oldResolved map[string]string
+	if oldLock != nil {
+		oldResolved = oldLock.Resolved
 	}
-	return nil
+	return DiffResolved(oldResolved, resolved), nil
 }
 
 // LastN selects the last N minor releases (including all patch releases) for a
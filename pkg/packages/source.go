@@ -0,0 +1,160 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package packages
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/google/go-github/v33/github"
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+// Source abstracts where release metadata and assets come from, so a
+// Package is not limited to fetching GitHub release artifacts. Operators
+// can mix and match Source implementations across a Package's Primary and
+// Additional sources.
+type Source interface {
+	// ListReleases returns every release known to this source for the
+	// given org/repo coordinates, most recent first not required: callers
+	// sort with LastN/releaseList as needed.
+	ListReleases(ctx context.Context, org, repo string) ([]Release, error)
+	// FetchAsset streams the contents of asset to w.
+	FetchAsset(ctx context.Context, asset Asset, w io.Writer) error
+}
+
+// GitHubSource implements Source against the GitHub releases API. It is
+// the original, and still default, way package assets are published.
+type GitHubSource struct {
+	Client *github.Client
+	HTTP   *retryablehttp.Client
+}
+
+// NewGitHubSource constructs a GitHubSource using the given HTTP client for
+// API calls, and a retrying client built on top of it for asset downloads.
+func NewGitHubSource(httpClient *http.Client) *GitHubSource {
+	retrying := retryablehttp.NewClient()
+	retrying.HTTPClient = httpClient
+	retrying.Logger = nil
+	return &GitHubSource{
+		Client: github.NewClient(httpClient),
+		HTTP:   retrying,
+	}
+}
+
+// ListReleases implements Source.
+func (s *GitHubSource) ListReleases(ctx context.Context, org, repo string) ([]Release, error) {
+	var releases []Release
+	opt := &github.ListOptions{PerPage: 100}
+	for {
+		ghReleases, resp, err := s.Client.Repositories.ListReleases(ctx, org, repo, opt)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list releases for %s/%s: %w", org, repo, err)
+		}
+		for _, r := range ghReleases {
+			assets := make(assetList, 0, len(r.Assets))
+			for _, a := range r.Assets {
+				assets = append(assets, Asset{Name: a.GetName(), URL: a.GetBrowserDownloadURL()})
+			}
+			releases = append(releases, Release{
+				Org:     org,
+				Repo:    repo,
+				TagName: r.GetTagName(),
+				Created: r.GetCreatedAt().Time,
+				Assets:  assets,
+			})
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	return releases, nil
+}
+
+// FetchAsset implements Source.
+func (s *GitHubSource) FetchAsset(ctx context.Context, asset Asset, w io.Writer) error {
+	return fetchRange(ctx, s.HTTP, asset.URL, 0, w)
+}
+
+// FetchAssetRange implements RangeSource, resuming a download starting at
+// offset via an HTTP Range request.
+func (s *GitHubSource) FetchAssetRange(ctx context.Context, asset Asset, offset int64, w io.Writer) error {
+	return fetchRange(ctx, s.HTTP, asset.URL, offset, w)
+}
+
+// HTTPSource implements Source against a plain HTTP directory of release
+// assets, for projects that publish manifests outside of GitHub releases.
+type HTTPSource struct {
+	Client  *retryablehttp.Client
+	BaseURL string
+}
+
+// NewHTTPSource constructs an HTTPSource backed by a retrying client, for
+// directories served over a less reliable connection than GitHub's CDN.
+func NewHTTPSource(baseURL string, httpClient *http.Client) *HTTPSource {
+	retrying := retryablehttp.NewClient()
+	retrying.HTTPClient = httpClient
+	retrying.Logger = nil
+	return &HTTPSource{Client: retrying, BaseURL: baseURL}
+}
+
+// ListReleases implements Source. HTTPSource has no release listing of its
+// own; callers are expected to supply Release values built from known tags.
+func (s *HTTPSource) ListReleases(ctx context.Context, org, repo string) ([]Release, error) {
+	return nil, fmt.Errorf("HTTPSource does not support listing releases for %s/%s; supply Release values directly", org, repo)
+}
+
+// FetchAsset implements Source.
+func (s *HTTPSource) FetchAsset(ctx context.Context, asset Asset, w io.Writer) error {
+	return fetchRange(ctx, s.Client, asset.URL, 0, w)
+}
+
+// FetchAssetRange implements RangeSource, resuming a download starting at
+// offset via an HTTP Range request.
+func (s *HTTPSource) FetchAssetRange(ctx context.Context, asset Asset, offset int64, w io.Writer) error {
+	return fetchRange(ctx, s.Client, asset.URL, offset, w)
+}
+
+// fetchRange issues a GET for url, optionally resuming from offset via a
+// Range header, retrying transient failures with backoff.
+func fetchRange(ctx context.Context, client *retryablehttp.Client, url string, offset int64, w io.Writer) error {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("Unable to build request for %s: %w", url, err)
+	}
+	req, err := retryablehttp.FromRequest(httpReq)
+	if err != nil {
+		return fmt.Errorf("Unable to build request for %s: %w", url, err)
+	}
+	if offset > 0 {
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Unable to fetch %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		return fmt.Errorf("Unable to fetch %s: status %s", url, resp.Status)
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
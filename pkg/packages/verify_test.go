@@ -0,0 +1,116 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package packages
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindSha256Sum(t *testing.T) {
+	const sums = "deadbeef  plain-file.yaml\n" +
+		"cafef00d *starred-file.yaml\n"
+
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "plain-file.yaml", want: "deadbeef"},
+		{name: "starred-file.yaml", want: "cafef00d"},
+		{name: "missing.yaml", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := findSha256Sum(sums, tt.name)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("findSha256Sum(%q) = %q, nil, want error", tt.name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("findSha256Sum(%q) = %v, want nil", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("findSha256Sum(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// stringSource serves a fixed body for any FetchAsset call, regardless of
+// asset.URL, so tests can stand in for the Source a Sha256Sums fetches its
+// SHA256SUMS file or asset bytes from.
+type stringSource struct {
+	body string
+}
+
+func (s stringSource) ListReleases(ctx context.Context, org, repo string) ([]Release, error) {
+	return nil, nil
+}
+
+func (s stringSource) FetchAsset(ctx context.Context, asset Asset, w io.Writer) error {
+	_, err := io.WriteString(w, s.body)
+	return err
+}
+
+func TestSha256SumsVerify(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "asset.yaml")
+	if err := os.WriteFile(path, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// sha256("hello")
+	const wantDigest = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	const wrongDigest = "0000000000000000000000000000000000000000000000000000000000000000"
+
+	t.Run("explicit digest matches", func(t *testing.T) {
+		asset := Asset{Name: "asset.yaml", Verification: Verification{SHA256: wantDigest}}
+		v := Sha256Sums{}
+		if err := v.Verify(context.Background(), asset, path); err != nil {
+			t.Errorf("Verify() = %v, want nil", err)
+		}
+	})
+
+	t.Run("explicit digest mismatch", func(t *testing.T) {
+		asset := Asset{Name: "asset.yaml", Verification: Verification{SHA256: wrongDigest}}
+		v := Sha256Sums{}
+		if err := v.Verify(context.Background(), asset, path); err == nil {
+			t.Error("Verify() = nil, want mismatch error")
+		}
+	})
+
+	t.Run("looked up from SHA256SUMS file", func(t *testing.T) {
+		sums := wantDigest + "  asset.yaml\n"
+		asset := Asset{Name: "asset.yaml", Verification: Verification{SHA256SumsURL: "http://example.com/SHA256SUMS"}}
+		v := Sha256Sums{Fetch: stringSource{body: sums}}
+		if err := v.Verify(context.Background(), asset, path); err != nil {
+			t.Errorf("Verify() = %v, want nil", err)
+		}
+	})
+
+	t.Run("name missing from SHA256SUMS file", func(t *testing.T) {
+		asset := Asset{Name: "other.yaml", Verification: Verification{SHA256SumsURL: "http://example.com/SHA256SUMS"}}
+		v := Sha256Sums{Fetch: stringSource{body: wantDigest + "  asset.yaml\n"}}
+		if err := v.Verify(context.Background(), asset, path); err == nil {
+			t.Error("Verify() = nil, want error for a name missing from the checksums file")
+		}
+	})
+}